@@ -0,0 +1,140 @@
+package assertive
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// WantEq marks the test as failed if got != want. Like Want, the test may
+// continue even if the assertion fails. Unlike Want, the failure message
+// includes the offending values, so a failure is actionable without
+// rerunning under a debugger.
+func WantEq[T comparable](t miniT, got, want T) {
+	t.Helper()
+
+	if got != want {
+		report(t, false, getLine(t), got, want)
+	}
+}
+
+// NeedEq is to WantEq as Need is to Want.
+func NeedEq[T comparable](t miniT, got, want T) {
+	t.Helper()
+
+	if got != want {
+		report(t, true, getLine(t), got, want)
+	}
+}
+
+// WantMatch marks the test as failed if got does not match re.
+func WantMatch(t miniT, got string, re *regexp.Regexp) {
+	t.Helper()
+
+	if !re.MatchString(got) {
+		report(t, false, getLine(t), got, "match of "+re.String())
+	}
+}
+
+// NeedMatch is to WantMatch as Need is to Want.
+func NeedMatch(t miniT, got string, re *regexp.Regexp) {
+	t.Helper()
+
+	if !re.MatchString(got) {
+		report(t, true, getLine(t), got, "match of "+re.String())
+	}
+}
+
+// WantGlob marks the test as failed if got does not match the shell file
+// name pattern, as defined by path/filepath.Match.
+func WantGlob(t miniT, got, pattern string) {
+	t.Helper()
+
+	matched, err := filepath.Match(pattern, got)
+	if err != nil || !matched {
+		report(t, false, getLine(t), got, "glob "+pattern)
+	}
+}
+
+// NeedGlob is to WantGlob as Need is to Want.
+func NeedGlob(t miniT, got, pattern string) {
+	t.Helper()
+
+	matched, err := filepath.Match(pattern, got)
+	if err != nil || !matched {
+		report(t, true, getLine(t), got, "glob "+pattern)
+	}
+}
+
+// WantContains marks the test as failed if haystack does not contain needle.
+func WantContains(t miniT, haystack, needle string) {
+	t.Helper()
+
+	if !strings.Contains(haystack, needle) {
+		report(t, false, getLine(t), haystack, "a string containing "+needle)
+	}
+}
+
+// NeedContains is to WantContains as Need is to Want.
+func NeedContains(t miniT, haystack, needle string) {
+	t.Helper()
+
+	if !strings.Contains(haystack, needle) {
+		report(t, true, getLine(t), haystack, "a string containing "+needle)
+	}
+}
+
+// WantErr marks the test as failed if errors.Is(err, target) is false.
+func WantErr(t miniT, err, target error) {
+	t.Helper()
+
+	if !errors.Is(err, target) {
+		report(t, false, getLine(t), err, target)
+	}
+}
+
+// NeedErr is to WantErr as Need is to Want.
+func NeedErr(t miniT, err, target error) {
+	t.Helper()
+
+	if !errors.Is(err, target) {
+		report(t, true, getLine(t), err, target)
+	}
+}
+
+// WantErrAs marks the test as failed if errors.As(err, target) is false.
+// Like errors.As, target must be a non-nil pointer to a type implementing
+// error, and is populated with the matching error on success.
+func WantErrAs[T error](t miniT, err error, target *T) {
+	t.Helper()
+
+	if !errors.As(err, target) {
+		report(t, false, getLine(t), err, fmt.Sprintf("an error matching %T", *target))
+	}
+}
+
+// NeedErrAs is to WantErrAs as Need is to Want.
+func NeedErrAs[T error](t miniT, err error, target *T) {
+	t.Helper()
+
+	if !errors.As(err, target) {
+		report(t, true, getLine(t), err, fmt.Sprintf("an error matching %T", *target))
+	}
+}
+
+// report appends a formatted got=... want=... block to the offending source
+// line, so the failure is legible without rerunning the assertion under a
+// debugger.
+func report(t miniT, fatal bool, line string, got, want any) {
+	t.Helper()
+
+	msg := fmt.Sprintf("%s\n\tgot=%v want=%v", line, got, want)
+
+	if fatal {
+		t.Fatal(msg)
+	} else {
+		t.Error(msg)
+	}
+}
@@ -0,0 +1,121 @@
+package assertive_test
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"testing"
+
+	. "cdop.pt/go/open/assertive"
+)
+
+func TestWantEq(t *testing.T) {
+	ft := &fakeT{logs: []string{}}
+
+	WantEq(ft, 11, 12)
+
+	Need(t, len(ft.logs) == 1)
+	Want(t, ft.stop == false)
+	Want(t, ft.logs[0] == "\tWantEq(ft, 11, 12)\n\tgot=11 want=12")
+
+	ft = &fakeT{logs: []string{}}
+
+	WantEq(ft, 13, 13)
+
+	Want(t, len(ft.logs) == 0)
+}
+
+func TestNeedEq(t *testing.T) {
+	ft := &fakeT{logs: []string{}}
+
+	NeedEq(ft, "a", "b")
+
+	Need(t, len(ft.logs) == 1)
+	Want(t, ft.stop == true)
+	Want(t, ft.logs[0] == "\tNeedEq(ft, \"a\", \"b\")\n\tgot=a want=b")
+}
+
+func TestWantMatch(t *testing.T) {
+	ft := &fakeT{logs: []string{}}
+
+	WantMatch(ft, "hello", regexp.MustCompile(`^goodbye`))
+
+	Need(t, len(ft.logs) == 1)
+	Want(t, ft.logs[0] == "\tWantMatch(ft, \"hello\", regexp.MustCompile(`^goodbye`))\n\tgot=hello want=match of ^goodbye")
+
+	ft = &fakeT{logs: []string{}}
+
+	WantMatch(ft, "hello world", regexp.MustCompile(`^hello`))
+
+	Want(t, len(ft.logs) == 0)
+}
+
+func TestWantGlob(t *testing.T) {
+	ft := &fakeT{logs: []string{}}
+
+	WantGlob(ft, "report.txt", "*.csv")
+
+	Need(t, len(ft.logs) == 1)
+	Want(t, ft.logs[0] == "\tWantGlob(ft, \"report.txt\", \"*.csv\")\n\tgot=report.txt want=glob *.csv")
+
+	ft = &fakeT{logs: []string{}}
+
+	WantGlob(ft, "report.csv", "*.csv")
+
+	Want(t, len(ft.logs) == 0)
+}
+
+func TestWantContains(t *testing.T) {
+	ft := &fakeT{logs: []string{}}
+
+	WantContains(ft, "hello world", "bye")
+
+	Need(t, len(ft.logs) == 1)
+	Want(t, ft.logs[0] == "\tWantContains(ft, \"hello world\", \"bye\")\n\tgot=hello world want=a string containing bye")
+
+	ft = &fakeT{logs: []string{}}
+
+	WantContains(ft, "hello world", "world")
+
+	Want(t, len(ft.logs) == 0)
+}
+
+var errSentinel = errors.New("sentinel")
+
+func TestWantErr(t *testing.T) {
+	ft := &fakeT{logs: []string{}}
+
+	WantErr(ft, errors.New("other"), errSentinel)
+
+	Need(t, len(ft.logs) == 1)
+	Want(t, ft.logs[0] == "\tWantErr(ft, errors.New(\"other\"), errSentinel)\n\tgot=other want=sentinel")
+
+	ft = &fakeT{logs: []string{}}
+
+	WantErr(ft, fmt.Errorf("wrapped: %w", errSentinel), errSentinel)
+
+	Want(t, len(ft.logs) == 0)
+}
+
+type customErr struct{ msg string }
+
+func (e *customErr) Error() string { return e.msg }
+
+func TestWantErrAs(t *testing.T) {
+	ft := &fakeT{logs: []string{}}
+
+	var target *customErr
+	WantErrAs(ft, errors.New("plain"), &target)
+
+	Need(t, len(ft.logs) == 1)
+	Want(t, ft.logs[0] == "\tWantErrAs(ft, errors.New(\"plain\"), &target)\n\tgot=plain want=an error matching *assertive_test.customErr")
+
+	ft = &fakeT{logs: []string{}}
+
+	target = nil
+	WantErrAs(ft, &customErr{msg: "boom"}, &target)
+
+	Want(t, len(ft.logs) == 0)
+	Need(t, target != nil)
+	Want(t, target.msg == "boom")
+}
@@ -0,0 +1,136 @@
+package linedb_test
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	. "cdop.pt/go/open/assertive"
+	"cdop.pt/go/open/linedb"
+)
+
+// TestConcurrentAccess spawns goroutines that each insert a uniquely marked
+// record, update it, then delete it again, all against the same Database,
+// and checks that the backing file parses back to the expected record set
+// once every goroutine is done. Run with -race to exercise db.mu.
+func TestConcurrentAccess(t *testing.T) {
+	fs, filePath := mkTestFS(t, "")
+
+	db, err := linedb.OpenFS(fs, filePath)
+	Need(t, err == nil)
+
+	const n = 20
+
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+
+		go func(i int) {
+			defer wg.Done()
+
+			marker := fmt.Sprintf("g%d", i)
+
+			if err := db.Insert(0, marker); err != nil {
+				panic(err)
+			}
+
+			updated := marker + "-updated"
+			updateMarker(db, marker, updated)
+			deleteMarker(db, updated)
+		}(i)
+	}
+
+	wg.Wait()
+
+	Want(t, db.Length() == 0)
+
+	buf, err := fs.ReadFile(filePath)
+	Need(t, err == nil)
+	Want(t, string(buf) == "")
+}
+
+// updateMarker finds the record with the given text and updates it to
+// newText. Select and Update are two separately-locked calls, so a
+// concurrent Insert or Delete elsewhere can shift the index between them and
+// cause this goroutine to update a different, unrelated record by mistake,
+// or to find a now out-of-bounds number. Either way, this goroutine retries
+// until it updates its own marker; a wrongly updated record is restored via
+// restoreUpdate first. The marker can be briefly missing while another
+// goroutine restores it, so a Select that finds nothing is retried too.
+func updateMarker(db *linedb.Database, text, newText string) {
+	for {
+		recs := db.Select(func(r linedb.Rec) bool { return r.Text == text })
+		if len(recs) == 0 {
+			continue
+		}
+
+		old, err := db.Update(recs[0].Number, newText)
+		if err != nil {
+			continue
+		}
+
+		if old == text {
+			return
+		}
+
+		restoreUpdate(db, recs[0].Number, old, newText)
+	}
+}
+
+// restoreUpdate puts victimText back at number, undoing an updateMarker call
+// that overwrote it by mistake. number may have shifted again since that
+// overwrite, in which case this would itself clobber whatever is there now;
+// to avoid losing it, anything found other than ourText (the value being
+// undone) is preserved by reinserting it. If number is no longer valid at
+// all, victimText is reinserted directly instead. In both of those cases
+// ourText has moved elsewhere rather than being overwritten, so that stray
+// copy is deleted as well.
+func restoreUpdate(db *linedb.Database, number int, victimText, ourText string) {
+	prev, err := db.Update(number, victimText)
+	if err != nil {
+		if err := db.Insert(0, victimText); err != nil {
+			panic(err)
+		}
+
+		deleteMarker(db, ourText)
+
+		return
+	}
+
+	if prev != ourText {
+		if err := db.Insert(0, prev); err != nil {
+			panic(err)
+		}
+
+		deleteMarker(db, ourText)
+	}
+}
+
+// deleteMarker finds the record with the given text and deletes it. Unlike
+// Update, Delete resizes the record slice, so a concurrent Delete elsewhere
+// can shift the index between Select and Delete and cause this goroutine to
+// remove a different, unrelated record by mistake. When that happens, the
+// wrongly removed record is reinserted so its rightful owner can still find
+// and delete it, and this goroutine retries until it removes its own,
+// including when a Select briefly finds nothing.
+func deleteMarker(db *linedb.Database, text string) {
+	for {
+		recs := db.Select(func(r linedb.Rec) bool { return r.Text == text })
+		if len(recs) == 0 {
+			continue
+		}
+
+		old, err := db.Delete(recs[0].Number)
+		if err != nil {
+			continue
+		}
+
+		if old == text {
+			return
+		}
+
+		if err := db.Insert(0, old); err != nil {
+			panic(err)
+		}
+	}
+}
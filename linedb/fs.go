@@ -0,0 +1,83 @@
+package linedb
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// FS abstracts the filesystem operations linedb needs to load and persist
+// its backing file. Passing a custom FS to OpenFS lets callers embed linedb
+// in read-only images, unit-test consumers without touching disk, or layer
+// caching/overlay filesystems on top of the real one.
+type FS interface {
+	ReadFile(name string) ([]byte, error)
+	WriteFile(name string, data []byte, perm os.FileMode) error
+	Stat(name string) (os.FileInfo, error)
+	Rename(oldpath, newpath string) error
+	Remove(name string) error
+	OpenFile(name string, flag int, perm os.FileMode) (File, error)
+}
+
+// File is the subset of *os.File that linedb needs from FS.OpenFile.
+type File interface {
+	io.Writer
+	io.Closer
+	Sync() error
+}
+
+// DirSyncer is implemented by filesystems that can fsync a directory. After
+// a durable commit renames its temporary file into place, linedb syncs the
+// containing directory on any FS implementing this interface, so the rename
+// itself survives a crash on POSIX systems. Implementing it is optional: a
+// FS that doesn't (e.g. MemFS) simply skips this step.
+type DirSyncer interface {
+	SyncDir(path string) error
+}
+
+// Locker is implemented by filesystems that support taking an advisory,
+// whole-file OS lock, used by OpenShared/OpenExclusive to coordinate
+// multiple processes sharing the same backing file. Lock returns a function
+// that releases the lock. Implementing it is optional: a FS that doesn't
+// (e.g. MemFS) simply means locking is skipped.
+type Locker interface {
+	Lock(path string, exclusive bool) (unlock func() error, err error)
+}
+
+// SyncDir opens the directory containing path and fsyncs it.
+func (OSFS) SyncDir(path string) error {
+	dir, err := os.Open(filepath.Dir(path))
+	if err != nil {
+		return err
+	}
+	defer dir.Close()
+
+	return dir.Sync()
+}
+
+// OSFS is the default FS, backed directly by the os package.
+type OSFS struct{}
+
+func (OSFS) ReadFile(name string) ([]byte, error) {
+	return os.ReadFile(name)
+}
+
+func (OSFS) WriteFile(name string, data []byte, perm os.FileMode) error {
+	return os.WriteFile(name, data, perm)
+}
+
+func (OSFS) Stat(name string) (os.FileInfo, error) {
+	return os.Stat(name)
+}
+
+func (OSFS) Rename(oldpath, newpath string) error {
+	return os.Rename(oldpath, newpath)
+}
+
+func (OSFS) Remove(name string) error {
+	return os.Remove(name)
+}
+
+func (OSFS) OpenFile(name string, flag int, perm os.FileMode) (File, error) {
+	return os.OpenFile(name, flag, perm)
+}
@@ -0,0 +1,316 @@
+package linedb
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Index maintains a map from a derived key to the numbers of the records
+// that produced it, so Lookup and LookupPrefix can answer queries without
+// scanning every record the way Select does. An Index is created with
+// CreateIndex and stays in sync with its Database as records are inserted,
+// updated, and deleted.
+type Index struct {
+	mu      sync.RWMutex
+	name    string
+	key     func(Rec) string
+	db      *Database
+	entries map[string][]int
+	keys    []string // sorted, unique keys of entries; backs LookupPrefix
+}
+
+// indexSnapshot is the on-disk representation of an Index, persisted by
+// Save and loaded by CreateIndex. Hash lets CreateIndex tell whether the
+// snapshot still matches the current backing file.
+type indexSnapshot struct {
+	Hash    string           `json:"hash"`
+	Entries map[string][]int `json:"entries"`
+}
+
+// CreateIndex builds an index named name, keyed by the result of calling key
+// on each record, and registers it to stay up to date as db is modified. If
+// a snapshot previously saved with Index.Save is found next to the backing
+// file and its content hash still matches db's current records, it's loaded
+// instead of rebuilding from scratch.
+func (db *Database) CreateIndex(name string, key func(Rec) string) *Index {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	idx := &Index{name: name, key: key, db: db}
+
+	if !idx.loadLocked() {
+		idx.rebuildLocked(db.records)
+	}
+
+	db.indexes = append(db.indexes, idx)
+
+	return idx
+}
+
+// Lookup returns the records whose key equals key.
+func (idx *Index) Lookup(key string) []Rec {
+	idx.mu.RLock()
+	numbers := append([]int(nil), idx.entries[key]...)
+	idx.mu.RUnlock()
+
+	return idx.records(numbers)
+}
+
+// LookupPrefix returns the records whose key starts with prefix, ordered by
+// record number.
+func (idx *Index) LookupPrefix(prefix string) []Rec {
+	idx.mu.RLock()
+	start := sort.SearchStrings(idx.keys, prefix)
+
+	var numbers []int
+	for _, k := range idx.keys[start:] {
+		if !strings.HasPrefix(k, prefix) {
+			break
+		}
+
+		numbers = append(numbers, idx.entries[k]...)
+	}
+	idx.mu.RUnlock()
+
+	sort.Ints(numbers)
+
+	return idx.records(numbers)
+}
+
+// records resolves record numbers to Recs, skipping any that no longer
+// exist. That can happen when a concurrent Delete shrinks the Database
+// between reading idx's entries and resolving them here.
+func (idx *Index) records(numbers []int) []Rec {
+	res := make([]Rec, 0, len(numbers))
+
+	for _, n := range numbers {
+		text, err := idx.db.Record(n)
+		if err != nil {
+			continue
+		}
+
+		res = append(res, Rec{n, text})
+	}
+
+	return res
+}
+
+// Save writes a snapshot of idx, together with a hash of the current backing
+// file, to the sidecar file "<path>.idx.<name>". CreateIndex uses it to skip
+// rebuilding the index the next time it's created against an unchanged file.
+func (idx *Index) Save() error {
+	idx.db.mu.RLock()
+	hash := contentHash(idx.db.records)
+	filePath := idx.db.sidecarPath(idx.name)
+	fs := idx.db.fs
+	idx.db.mu.RUnlock()
+
+	idx.mu.RLock()
+	entries := make(map[string][]int, len(idx.entries))
+	for k, v := range idx.entries {
+		entries[k] = append([]int(nil), v...)
+	}
+	idx.mu.RUnlock()
+
+	buf, err := json.Marshal(indexSnapshot{Hash: hash, Entries: entries})
+	if err != nil {
+		return fmt.Errorf("cannot marshal index %s: %w", idx.name, err)
+	}
+
+	if err := fs.WriteFile(filePath, buf, 0600); err != nil {
+		return fmt.Errorf("cannot save index %s: %w", idx.name, err)
+	}
+
+	return nil
+}
+
+// loadLocked loads idx's entries from its sidecar file, assuming the caller
+// holds db.mu. It returns false, leaving idx untouched, if there's no
+// sidecar file, it can't be parsed, or its hash no longer matches db's
+// current records.
+func (idx *Index) loadLocked() bool {
+	buf, err := idx.db.fs.ReadFile(idx.db.sidecarPath(idx.name))
+	if err != nil {
+		return false
+	}
+
+	var snapshot indexSnapshot
+	if err := json.Unmarshal(buf, &snapshot); err != nil {
+		return false
+	}
+
+	if snapshot.Hash != contentHash(idx.db.records) {
+		return false
+	}
+
+	idx.entries = snapshot.Entries
+	idx.keys = make([]string, 0, len(snapshot.Entries))
+
+	for k, nums := range snapshot.Entries {
+		sort.Ints(nums)
+		idx.keys = append(idx.keys, k)
+	}
+
+	sort.Strings(idx.keys)
+
+	return true
+}
+
+// rebuildLocked recomputes idx.entries and idx.keys from scratch against
+// records, assuming the caller holds db.mu.
+func (idx *Index) rebuildLocked(records []string) {
+	idx.entries = make(map[string][]int, len(records))
+
+	for i, text := range records {
+		k := idx.key(Rec{i + 1, text})
+		idx.entries[k] = append(idx.entries[k], i+1)
+	}
+
+	idx.keys = make([]string, 0, len(idx.entries))
+	for k := range idx.entries {
+		idx.keys = append(idx.keys, k)
+	}
+
+	sort.Strings(idx.keys)
+}
+
+// insert updates idx for a new record, numbered number, having just been
+// inserted. Every existing entry numbered number or higher shifts up by one
+// to make room for it.
+func (idx *Index) insert(number int, text string) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	idx.shiftLocked(number, 1)
+	idx.addLocked(number, text)
+}
+
+// update updates idx for the record numbered number changing its text from
+// oldText to newText.
+func (idx *Index) update(number int, oldText, newText string) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	idx.removeLocked(number, oldText)
+	idx.addLocked(number, newText)
+}
+
+// delete updates idx for the record numbered number, holding oldText, having
+// just been removed. Every remaining entry numbered above number shifts down
+// by one to close the gap.
+func (idx *Index) delete(number int, oldText string) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	idx.removeLocked(number, oldText)
+	idx.shiftLocked(number, -1)
+}
+
+func (idx *Index) shiftLocked(from, by int) {
+	for _, numbers := range idx.entries {
+		for i, n := range numbers {
+			if (by > 0 && n >= from) || (by < 0 && n > from) {
+				numbers[i] = n + by
+			}
+		}
+	}
+}
+
+func (idx *Index) addLocked(number int, text string) {
+	k := idx.key(Rec{number, text})
+
+	numbers, ok := idx.entries[k]
+	if !ok {
+		i := sort.SearchStrings(idx.keys, k)
+		idx.keys = append(idx.keys, "")
+		copy(idx.keys[i+1:], idx.keys[i:])
+		idx.keys[i] = k
+	}
+
+	i := sort.SearchInts(numbers, number)
+	numbers = append(numbers, 0)
+	copy(numbers[i+1:], numbers[i:])
+	numbers[i] = number
+	idx.entries[k] = numbers
+}
+
+func (idx *Index) removeLocked(number int, text string) {
+	k := idx.key(Rec{number, text})
+
+	numbers := idx.entries[k]
+
+	i := sort.SearchInts(numbers, number)
+	if i >= len(numbers) || numbers[i] != number {
+		return
+	}
+
+	numbers = append(numbers[:i], numbers[i+1:]...)
+
+	if len(numbers) == 0 {
+		delete(idx.entries, k)
+
+		i := sort.SearchStrings(idx.keys, k)
+		if i < len(idx.keys) && idx.keys[i] == k {
+			idx.keys = append(idx.keys[:i], idx.keys[i+1:]...)
+		}
+
+		return
+	}
+
+	idx.entries[k] = numbers
+}
+
+// sidecarPath returns the path of the sidecar file an index named name
+// persists its snapshot to.
+func (db *Database) sidecarPath(name string) string {
+	return db.filePath + ".idx." + name
+}
+
+// contentHash returns a hex-encoded hash of records in the same form
+// saveToFile writes them to the backing file, so it changes exactly when the
+// backing file's contents do.
+func contentHash(records []string) string {
+	sum := sha256.Sum256([]byte(strings.Join(records, "\n")))
+	return hex.EncodeToString(sum[:])
+}
+
+// notifyIndexesInsert tells every registered index about a new record,
+// assuming the caller holds db.mu for writing.
+func (db *Database) notifyIndexesInsert(number int, text string) {
+	for _, idx := range db.indexes {
+		idx.insert(number, text)
+	}
+}
+
+// notifyIndexesUpdate tells every registered index about a record whose text
+// changed, assuming the caller holds db.mu for writing.
+func (db *Database) notifyIndexesUpdate(number int, oldText, newText string) {
+	for _, idx := range db.indexes {
+		idx.update(number, oldText, newText)
+	}
+}
+
+// notifyIndexesDelete tells every registered index about a removed record,
+// assuming the caller holds db.mu for writing.
+func (db *Database) notifyIndexesDelete(number int, oldText string) {
+	for _, idx := range db.indexes {
+		idx.delete(number, oldText)
+	}
+}
+
+// rebuildIndexesLocked rebuilds every registered index from scratch against
+// db's current records, assuming the caller holds db.mu for writing. Tx
+// commits replace the whole record set in one step, so patching indexes
+// incrementally per buffered operation isn't an option; a full rebuild is.
+func (db *Database) rebuildIndexesLocked() {
+	for _, idx := range db.indexes {
+		idx.mu.Lock()
+		idx.rebuildLocked(db.records)
+		idx.mu.Unlock()
+	}
+}
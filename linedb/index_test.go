@@ -0,0 +1,130 @@
+package linedb_test
+
+import (
+	"strings"
+	"testing"
+
+	. "cdop.pt/go/open/assertive"
+	"cdop.pt/go/open/linedb"
+)
+
+func firstWord(r linedb.Rec) string {
+	if i := strings.IndexByte(r.Text, ' '); i >= 0 {
+		return r.Text[:i]
+	}
+
+	return r.Text
+}
+
+func TestCreateIndexBuildsFromExistingRecords(t *testing.T) {
+	fs, filePath := mkTestFS(t, "bob hi\nann hi\nbob bye")
+
+	db, err := linedb.OpenFS(fs, filePath)
+	Need(t, err == nil)
+
+	idx := db.CreateIndex("author", firstWord)
+
+	Want(t, sliceEq([]int{1, 3}, recNumbers(idx.Lookup("bob"))))
+	Want(t, sliceEq([]int{2}, recNumbers(idx.Lookup("ann"))))
+	Want(t, len(idx.Lookup("carl")) == 0)
+}
+
+func TestIndexLookupPrefix(t *testing.T) {
+	fs, filePath := mkTestFS(t, "ant 1\nant 2\nbee 1\nantelope 1")
+
+	db, err := linedb.OpenFS(fs, filePath)
+	Need(t, err == nil)
+
+	idx := db.CreateIndex("author", firstWord)
+
+	Want(t, sliceEq([]int{1, 2, 4}, recNumbers(idx.LookupPrefix("ant"))))
+	Want(t, sliceEq([]int{3}, recNumbers(idx.LookupPrefix("bee"))))
+	Want(t, len(idx.LookupPrefix("cat")) == 0)
+}
+
+func TestIndexTracksInsertUpdateDelete(t *testing.T) {
+	fs, filePath := mkTestFS(t, "bob hi")
+
+	db, err := linedb.OpenFS(fs, filePath)
+	Need(t, err == nil)
+
+	idx := db.CreateIndex("author", firstWord)
+
+	Need(t, db.Insert(1, "ann hi") == nil)
+	Want(t, sliceEq([]int{1}, recNumbers(idx.Lookup("ann"))))
+	Want(t, sliceEq([]int{2}, recNumbers(idx.Lookup("bob"))))
+
+	_, err = db.Update(2, "carl hi")
+	Need(t, err == nil)
+	Want(t, len(idx.Lookup("bob")) == 0)
+	Want(t, sliceEq([]int{2}, recNumbers(idx.Lookup("carl"))))
+
+	_, err = db.Delete(1)
+	Need(t, err == nil)
+	Want(t, len(idx.Lookup("ann")) == 0)
+	Want(t, sliceEq([]int{1}, recNumbers(idx.Lookup("carl"))))
+}
+
+func TestIndexRebuildsAfterTxCommit(t *testing.T) {
+	fs, filePath := mkTestFS(t, "bob hi")
+
+	db, err := linedb.OpenFS(fs, filePath)
+	Need(t, err == nil)
+
+	idx := db.CreateIndex("author", firstWord)
+
+	tx := db.Begin()
+	Need(t, tx.Insert(0, "ann hi") == nil)
+	Need(t, tx.Commit() == nil)
+
+	Want(t, sliceEq([]int{1}, recNumbers(idx.Lookup("bob"))))
+	Want(t, sliceEq([]int{2}, recNumbers(idx.Lookup("ann"))))
+}
+
+func TestIndexSaveAndReload(t *testing.T) {
+	fs, filePath := mkTestFS(t, "bob hi\nann hi")
+
+	db, err := linedb.OpenFS(fs, filePath)
+	Need(t, err == nil)
+
+	idx := db.CreateIndex("author", firstWord)
+	Need(t, idx.Save() == nil)
+
+	reloaded, err := linedb.OpenFS(fs, filePath)
+	Need(t, err == nil)
+
+	calls := 0
+	idx2 := reloaded.CreateIndex("author", func(r linedb.Rec) string {
+		calls++
+		return firstWord(r)
+	})
+
+	Want(t, calls == 0)
+	Want(t, sliceEq([]int{1}, recNumbers(idx2.Lookup("bob"))))
+}
+
+func TestIndexRebuildsOnStaleSnapshot(t *testing.T) {
+	fs, filePath := mkTestFS(t, "bob hi")
+
+	db, err := linedb.OpenFS(fs, filePath)
+	Need(t, err == nil)
+
+	idx := db.CreateIndex("author", firstWord)
+	Need(t, idx.Save() == nil)
+	Need(t, db.Insert(0, "ann hi") == nil)
+
+	reloaded, err := linedb.OpenFS(fs, filePath)
+	Need(t, err == nil)
+
+	idx2 := reloaded.CreateIndex("author", firstWord)
+	Want(t, sliceEq([]int{2}, recNumbers(idx2.Lookup("ann"))))
+}
+
+func recNumbers(recs []linedb.Rec) []int {
+	numbers := make([]int, len(recs))
+	for i, r := range recs {
+		numbers[i] = r.Number
+	}
+
+	return numbers
+}
@@ -15,12 +15,21 @@ import (
 	"fmt"
 	"os"
 	"strings"
+	"sync"
 )
 
-// Database represents a handle to a given backing file.
+// Database represents a handle to a given backing file. A *Database is safe
+// for concurrent use by multiple goroutines; see OpenShared and
+// OpenExclusive for coordinating multiple processes sharing the same
+// backing file.
 type Database struct {
+	mu       sync.RWMutex
+	fs       FS
 	filePath string
 	records  []string
+	opts     Options
+	unlock   func() error
+	indexes  []*Index
 }
 
 // Rec pairs the text of a record with its line number in the backing file.
@@ -29,26 +38,112 @@ type Rec struct {
 	Text   string
 }
 
-// Open creates a handle to the given backing file.
-func Open(filePath string) (*Database, error) {
-	records, err := loadFromFile(filePath)
+// Options controls how a Database persists its backing file. Zero-valued
+// fields fall back to their defaults; Mode defaults to 0600 and TmpSuffix
+// defaults to ".tmp".
+type Options struct {
+	// Durable makes every commit fsync the temporary file, and the backing
+	// directory on filesystems that support it, before returning. This
+	// trades write latency for a guarantee that a committed change survives
+	// a crash or power loss. Off by default.
+	Durable bool
+
+	// Mode is the permission mode used when a save finds no backing file to
+	// take the mode from (Open itself fails if the file doesn't exist). It is
+	// ignored while the file exists; saves preserve the existing file's mode.
+	// Defaults to 0600.
+	Mode os.FileMode
+
+	// TmpSuffix is appended to filePath to name the temporary file each
+	// commit is staged in before being renamed over the backing file.
+	// Defaults to ".tmp".
+	TmpSuffix string
+
+	// Lock selects the advisory OS-level lock acquired on Open, for
+	// coordinating multiple processes sharing the same backing file.
+	// Defaults to NoLock. OpenShared and OpenExclusive set this for you.
+	Lock LockMode
+}
+
+var defaultOptions = Options{Mode: 0600, TmpSuffix: ".tmp"}
+
+func resolveOptions(opts []Options) Options {
+	if len(opts) == 0 {
+		return defaultOptions
+	}
+
+	o := opts[0]
+	if o.Mode == 0 {
+		o.Mode = defaultOptions.Mode
+	}
+	if o.TmpSuffix == "" {
+		o.TmpSuffix = defaultOptions.TmpSuffix
+	}
 
+	return o
+}
+
+// Open creates a handle to the given backing file, using the real
+// filesystem and, unless opts sets Lock, no OS-level locking. Use OpenShared
+// or OpenExclusive to also coordinate with other processes, or OpenFS to
+// supply a different FS, e.g. for testing. An optional Options controls
+// durability, the temp file used for commits, and locking.
+func Open(filePath string, opts ...Options) (*Database, error) {
+	return OpenFS(OSFS{}, filePath, opts...)
+}
+
+// OpenFS creates a handle to the given backing file through fs, rather than
+// through the real filesystem.
+func OpenFS(fs FS, filePath string, opts ...Options) (*Database, error) {
+	records, err := loadFromFile(fs, filePath)
 	if err != nil {
 		return nil, err
 	}
 
-	return &Database{filePath, records}, nil
+	o := resolveOptions(opts)
+
+	unlock, err := acquireLock(fs, filePath, o.Lock)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Database{fs: fs, filePath: filePath, records: records, opts: o, unlock: unlock}, nil
+}
+
+// Close releases the OS-level lock acquired by OpenShared or OpenExclusive,
+// if any. It is a no-op for databases opened without locking.
+func (db *Database) Close() error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if db.unlock == nil {
+		return nil
+	}
+
+	unlock := db.unlock
+	db.unlock = nil
+
+	return unlock()
 }
 
 // Record returns the number-th record in the database. Like lines in files,
 // records are 1-indexed.
 func (db *Database) Record(number int) (string, error) {
-	err := db.checkEmpty()
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	return db.recordLocked(number)
+}
+
+// recordLocked is Record's implementation, assuming the caller already
+// holds db.mu for reading (or writing).
+func (db *Database) recordLocked(number int) (string, error) {
+	err := checkRecordsEmpty(len(db.records))
 	if err != nil {
 		return "", err
 	}
 
-	err = db.checkBounds(1, number)
+	err = checkRecordsBounds(len(db.records), 1, number)
 	if err != nil {
 		return "", err
 	}
@@ -58,12 +153,18 @@ func (db *Database) Record(number int) (string, error) {
 
 // Length returns the number of records in the database.
 func (db *Database) Length() int {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
 	return len(db.records)
 }
 
 // All returns all the records with their respective number
 func (db *Database) All() []Rec {
-	records := make([]Rec, db.Length())
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	records := make([]Rec, len(db.records))
 
 	for i := range db.records {
 		records[i] = Rec{i + 1, db.records[i]}
@@ -74,6 +175,9 @@ func (db *Database) All() []Rec {
 
 // Select returns the records which cause the filter function to return true.
 func (db *Database) Select(filter func(Rec) bool) []Rec {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
 	res := []Rec{}
 
 	for i := range db.records {
@@ -94,13 +198,16 @@ func (db *Database) Select(filter func(Rec) bool) []Rec {
 //
 // Special case: Insert(0, ...) places the new record at the end of the file.
 func (db *Database) Insert(number int, record string) error {
-	err := db.checkBounds(0, number)
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	err := checkRecordsBounds(len(db.records), 0, number)
 	if err != nil {
 		return err
 	}
 
 	// special case: inserting empty record on an empty database is a noop
-	if db.Length() == 0 && record == "" {
+	if len(db.records) == 0 && record == "" {
 		return nil
 	}
 
@@ -108,59 +215,69 @@ func (db *Database) Insert(number int, record string) error {
 	if number == 0 {
 		newRecords := append(db.records, record)
 
-		err := saveToFile(db.filePath, newRecords)
+		err := saveToFile(db.fs, db.filePath, newRecords, db.opts)
 		if err != nil {
 			return err
 		}
 
 		db.records = newRecords
+		db.notifyIndexesInsert(len(newRecords), record)
 
 		return nil
 	}
 
 	// general case:
-	newRecords := make([]string, 0, db.Length()+1)
+	newRecords := make([]string, 0, len(db.records)+1)
 	newRecords = append(newRecords, db.records[0:number-1]...)
 	newRecords = append(newRecords, record)
 	newRecords = append(newRecords, db.records[number-1:]...)
 
-	err = saveToFile(db.filePath, newRecords)
+	err = saveToFile(db.fs, db.filePath, newRecords, db.opts)
 	if err != nil {
 		return err
 	}
 
 	db.records = newRecords
+	db.notifyIndexesInsert(number, record)
 
 	return nil
 }
 
 // Update replaces the text of the number-th record, and returns the old text.
 func (db *Database) Update(number int, record string) (string, error) {
-	old, err := db.Record(number)
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	old, err := db.recordLocked(number)
 	if err != nil {
 		return "", err
 	}
 
 	db.records[number-1] = record
 
-	err = saveToFile(db.filePath, db.records)
+	err = saveToFile(db.fs, db.filePath, db.records, db.opts)
 	if err != nil {
 		db.records[number-1] = old
 		return "", err
 	}
 
+	db.notifyIndexesUpdate(number, old, record)
+
 	return old, nil
 
 }
 
 // Delete removes the number-th record from the database.
 func (db *Database) Delete(number int) (string, error) {
-	old, err := db.Record(number)
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	old, err := db.recordLocked(number)
 	if err != nil {
 		return "", err
 	}
 
-	newRecords := make([]string, 0, db.Length()-1)
+	newRecords := make([]string, 0, len(db.records)-1)
 	for i := range db.records {
 		if i == number-1 {
 			continue
@@ -169,27 +286,26 @@ func (db *Database) Delete(number int) (string, error) {
 		newRecords = append(newRecords, db.records[i])
 	}
 
-	err = saveToFile(db.filePath, newRecords)
+	err = saveToFile(db.fs, db.filePath, newRecords, db.opts)
 	if err != nil {
 		return "", err
 	}
 
 	db.records = newRecords
+	db.notifyIndexesDelete(number, old)
 
 	return old, nil
 }
 
-func (db *Database) checkEmpty() error {
-	if db.Length() == 0 {
+func checkRecordsEmpty(numberOfRecords int) error {
+	if numberOfRecords == 0 {
 		return fmt.Errorf("no records in database")
 	}
 
 	return nil
 }
 
-func (db *Database) checkBounds(lower, number int) error {
-	numberOfRecords := db.Length()
-
+func checkRecordsBounds(numberOfRecords, lower, number int) error {
 	if number < lower || number > numberOfRecords {
 		return fmt.Errorf("record number (%d) out of bounds [%d, %d]",
 			number, lower, numberOfRecords)
@@ -198,8 +314,8 @@ func (db *Database) checkBounds(lower, number int) error {
 	return nil
 }
 
-func loadFromFile(filePath string) ([]string, error) {
-	buf, err := os.ReadFile(filePath)
+func loadFromFile(fs FS, filePath string) ([]string, error) {
+	buf, err := fs.ReadFile(filePath)
 	if err != nil {
 		return nil, fmt.Errorf("cannot open linedb backing file %s: %w", filePath, err)
 	}
@@ -213,11 +329,55 @@ func loadFromFile(filePath string) ([]string, error) {
 	return records, nil
 }
 
-func saveToFile(filePath string, records []string) error {
-	err := os.WriteFile(filePath, []byte(strings.Join(records, "\n")), 0)
+// saveToFile commits records by writing them to a temporary file in the same
+// directory as filePath and renaming it into place, so a crash or power loss
+// partway through a write never corrupts or truncates the backing file. The
+// temporary file is created with the backing file's existing mode, or
+// opts.Mode if the backing file doesn't exist yet.
+func saveToFile(fs FS, filePath string, records []string, opts Options) error {
+	mode := opts.Mode
+	if info, err := fs.Stat(filePath); err == nil {
+		mode = info.Mode()
+	}
+
+	tmpPath := filePath + opts.TmpSuffix
+
+	f, err := fs.OpenFile(tmpPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode)
 	if err != nil {
+		return fmt.Errorf("cannot create temporary file for %s: %w", filePath, err)
+	}
+
+	if _, err := f.Write([]byte(strings.Join(records, "\n"))); err != nil {
+		f.Close()
+		fs.Remove(tmpPath)
+		return fmt.Errorf("cannot write temporary file for %s: %w", filePath, err)
+	}
+
+	if opts.Durable {
+		if err := f.Sync(); err != nil {
+			f.Close()
+			fs.Remove(tmpPath)
+			return fmt.Errorf("cannot sync temporary file for %s: %w", filePath, err)
+		}
+	}
+
+	if err := f.Close(); err != nil {
+		fs.Remove(tmpPath)
+		return fmt.Errorf("cannot close temporary file for %s: %w", filePath, err)
+	}
+
+	if err := fs.Rename(tmpPath, filePath); err != nil {
+		fs.Remove(tmpPath)
 		return fmt.Errorf("cannot save linedb backing file %s: %w", filePath, err)
 	}
 
+	if opts.Durable {
+		if ds, ok := fs.(DirSyncer); ok {
+			if err := ds.SyncDir(filePath); err != nil {
+				return fmt.Errorf("cannot sync directory for %s: %w", filePath, err)
+			}
+		}
+	}
+
 	return nil
 }
@@ -1,6 +1,7 @@
 package linedb_test
 
 import (
+	"errors"
 	"os"
 	"strings"
 	"testing"
@@ -10,13 +11,32 @@ import (
 )
 
 func TestLoadFailure(t *testing.T) {
-	filePath := "/tmp/no-such-file-or-directory"
+	fs := linedb.NewMemFS()
 
-	db, err := linedb.Open(filePath)
+	db, err := linedb.OpenFS(fs, "/no-such-file-or-directory")
 	Want(t, err != nil)
 	Want(t, db == nil)
 }
 
+func TestSaveDurablePreservesMode(t *testing.T) {
+	fs, filePath := mkTestFS(t, "ln1\nln2")
+
+	// Mode differs from the file's 0600, so 0600 afterwards means it was kept
+	db, err := linedb.OpenFS(fs, filePath, linedb.Options{Durable: true, Mode: 0644})
+	Need(t, err == nil)
+
+	_, err = db.Update(1, "new")
+	Need(t, err == nil)
+
+	buf, err := fs.ReadFile(filePath)
+	Need(t, err == nil)
+	Want(t, string(buf) == "new\nln2")
+
+	info, err := fs.Stat(filePath)
+	Need(t, err == nil)
+	Want(t, info.Mode() == 0600)
+}
+
 func TestRecord(t *testing.T) {
 	tests := []struct {
 		name   string
@@ -35,10 +55,9 @@ func TestRecord(t *testing.T) {
 	for _, x := range tests {
 		t.Run(x.name, func(t *testing.T) {
 
-			filePath := mkTestFile(t, x.dbCont)
-			defer os.Remove(filePath)
+			fs, filePath := mkTestFS(t, x.dbCont)
 
-			db, err := linedb.Open(filePath)
+			db, err := linedb.OpenFS(fs, filePath)
 			Need(t, err == nil)
 
 			text, err := db.Record(x.number)
@@ -83,10 +102,9 @@ func TestSelect(t *testing.T) {
 
 		t.Run(x.name, func(t *testing.T) {
 
-			filePath := mkTestFile(t, x.dbCont)
-			defer os.Remove(filePath)
+			fs, filePath := mkTestFS(t, x.dbCont)
 
-			db, err := linedb.Open(filePath)
+			db, err := linedb.OpenFS(fs, filePath)
 			Need(t, err == nil)
 
 			records := db.Select(func(r linedb.Rec) bool {
@@ -124,10 +142,9 @@ func TestInsert(t *testing.T) {
 	for _, x := range tests {
 		t.Run(x.name, func(t *testing.T) {
 
-			filePath := mkTestFile(t, x.dbContS)
-			defer os.Remove(filePath)
+			fs, filePath := mkTestFS(t, x.dbContS)
 
-			db, err := linedb.Open(filePath)
+			db, err := linedb.OpenFS(fs, filePath)
 			Need(t, err == nil)
 
 			err = db.Insert(x.number, x.text)
@@ -139,7 +156,7 @@ func TestInsert(t *testing.T) {
 				Want(t, err.Error() == x.err)
 			}
 
-			buf, err := os.ReadFile(filePath)
+			buf, err := fs.ReadFile(filePath)
 			Need(t, err == nil)
 
 			Want(t, db.Length() == x.length)
@@ -167,17 +184,12 @@ func TestInsertFail(t *testing.T) {
 		recs := x.recs
 
 		t.Run(x.name, func(t *testing.T) {
-			filePath := mkTestFile(t, dbContS)
-			defer os.Remove(filePath)
+			fs, filePath := mkFailingTestFS(t, dbContS)
 
-			db, err := linedb.Open(filePath)
+			db, err := linedb.OpenFS(fs, filePath)
 			Need(t, err == nil)
 			Want(t, db != nil)
 
-			err = os.Chmod(filePath, 0400)
-			Need(t, err == nil)
-			defer os.Chmod(filePath, 0600)
-
 			err = db.Insert(number, "text")
 			Need(t, err != nil)
 
@@ -208,10 +220,9 @@ func TestUpdate(t *testing.T) {
 	for _, x := range tests {
 		t.Run(x.name, func(t *testing.T) {
 
-			filePath := mkTestFile(t, x.dbContS)
-			defer os.Remove(filePath)
+			fs, filePath := mkTestFS(t, x.dbContS)
 
-			db, err := linedb.Open(filePath)
+			db, err := linedb.OpenFS(fs, filePath)
 			Need(t, err == nil)
 
 			old, err := db.Update(x.number, x.rec)
@@ -224,7 +235,7 @@ func TestUpdate(t *testing.T) {
 			}
 			Want(t, old == x.old)
 
-			buf, err := os.ReadFile(filePath)
+			buf, err := fs.ReadFile(filePath)
 			Need(t, err == nil)
 
 			Want(t, string(buf) == x.dbContE)
@@ -234,21 +245,16 @@ func TestUpdate(t *testing.T) {
 }
 
 func TestUpdateFail(t *testing.T) {
-	filePath := mkTestFile(t, "initial")
-	defer os.Remove(filePath)
+	fs, filePath := mkFailingTestFS(t, "initial")
 
-	db, err := linedb.Open(filePath)
+	db, err := linedb.OpenFS(fs, filePath)
 	Need(t, err == nil)
 	Want(t, db != nil)
 
-	err = os.Chmod(filePath, 0400)
-	Need(t, err == nil)
-	defer os.Chmod(filePath, 0600)
-
 	_, err = db.Update(1, "new")
 	Need(t, err != nil)
 
-	buf, err := os.ReadFile(filePath)
+	buf, err := fs.ReadFile(filePath)
 	Need(t, err == nil)
 
 	Want(t, string(buf) == "initial")
@@ -276,10 +282,9 @@ func TestDelete(t *testing.T) {
 	for _, x := range tests {
 		t.Run(x.name, func(t *testing.T) {
 
-			filePath := mkTestFile(t, x.dbContS)
-			defer os.Remove(filePath)
+			fs, filePath := mkTestFS(t, x.dbContS)
 
-			db, err := linedb.Open(filePath)
+			db, err := linedb.OpenFS(fs, filePath)
 			Need(t, err == nil)
 
 			text, err := db.Delete(x.number)
@@ -292,7 +297,7 @@ func TestDelete(t *testing.T) {
 			}
 			Want(t, text == x.text)
 
-			buf, err := os.ReadFile(filePath)
+			buf, err := fs.ReadFile(filePath)
 			Need(t, err == nil)
 
 			Want(t, db.Length() == x.length)
@@ -303,42 +308,61 @@ func TestDelete(t *testing.T) {
 }
 
 func TestDeleteFail(t *testing.T) {
-	filePath := mkTestFile(t, "initial")
-	defer os.Remove(filePath)
+	fs, filePath := mkFailingTestFS(t, "initial")
 
-	db, err := linedb.Open(filePath)
+	db, err := linedb.OpenFS(fs, filePath)
 	Need(t, err == nil)
 	Want(t, db != nil)
 
-	err = os.Chmod(filePath, 0400)
-	Need(t, err == nil)
-	defer os.Chmod(filePath, 0600)
-
 	_, err = db.Delete(1)
 	Need(t, err != nil)
 
-	buf, err := os.ReadFile(filePath)
+	buf, err := fs.ReadFile(filePath)
 	Need(t, err == nil)
 
 	Want(t, string(buf) == "initial")
 }
 
-func mkTestFile(t *testing.T, content string) string {
-	f, err := os.CreateTemp("", "linedb")
-	Need(t, err == nil)
+// mkTestFS returns a fresh in-memory FS with a single writable database file
+// at a fixed path, seeded with content.
+func mkTestFS(t *testing.T, content string) (linedb.FS, string) {
+	t.Helper()
 
-	name := f.Name()
+	const filePath = "/db"
 
-	_, err = f.WriteString(content)
+	fs := linedb.NewMemFS()
+	err := fs.WriteFile(filePath, []byte(content), 0600)
 	Need(t, err == nil)
 
-	err = f.Sync()
-	Need(t, err == nil)
+	return fs, filePath
+}
 
-	err = f.Close()
-	Need(t, err == nil)
+// mkFailingTestFS is like mkTestFS, but every commit's write-temp-then-rename
+// fails while creating the temporary file, so the backing file is never
+// touched. Since a commit only needs write permission on the directory (not
+// on the target file) to rename over it, the old real-filesystem trick of
+// os.Chmod(filePath, 0400) no longer simulates a failed write; this does.
+func mkFailingTestFS(t *testing.T, content string) (linedb.FS, string) {
+	t.Helper()
+
+	fs, filePath := mkTestFS(t, content)
+
+	return failingFS{FS: fs, failSubstr: ".tmp"}, filePath
+}
+
+// failingFS wraps a FS and fails OpenFile for any path containing
+// failSubstr, to simulate a commit that can't create its temporary file.
+type failingFS struct {
+	linedb.FS
+	failSubstr string
+}
+
+func (fs failingFS) OpenFile(name string, flag int, perm os.FileMode) (linedb.File, error) {
+	if strings.Contains(name, fs.failSubstr) {
+		return nil, errors.New("simulated write failure")
+	}
 
-	return name
+	return fs.FS.OpenFile(name, flag, perm)
 }
 
 func sliceEq[T comparable](a, b []T) bool {
@@ -0,0 +1,67 @@
+package linedb_test
+
+import (
+	"fmt"
+	"testing"
+
+	. "cdop.pt/go/open/assertive"
+	"cdop.pt/go/open/linedb"
+)
+
+func TestCloseWithoutLockIsNoop(t *testing.T) {
+	fs, filePath := mkTestFS(t, "ln1")
+
+	db, err := linedb.OpenFS(fs, filePath)
+	Need(t, err == nil)
+
+	err = db.Close()
+	Want(t, err == nil)
+}
+
+func TestOpenSharedSkipsLockingWithoutLocker(t *testing.T) {
+	fs, filePath := mkTestFS(t, "ln1")
+
+	db, err := linedb.OpenFS(fs, filePath, linedb.Options{Lock: linedb.SharedLock})
+	Need(t, err == nil)
+	Want(t, db != nil)
+
+	Want(t, db.Close() == nil)
+}
+
+// lockingFS wraps a FS and implements Locker, simulating a filesystem whose
+// backing file is already locked by another process.
+type lockingFS struct {
+	linedb.FS
+	locked bool
+}
+
+func (fs *lockingFS) Lock(path string, exclusive bool) (func() error, error) {
+	if fs.locked {
+		return nil, fmt.Errorf("%w: %s", linedb.ErrLocked, path)
+	}
+
+	fs.locked = true
+
+	return func() error {
+		fs.locked = false
+		return nil
+	}, nil
+}
+
+func TestOpenExclusiveContention(t *testing.T) {
+	fs, filePath := mkTestFS(t, "ln1")
+	locking := &lockingFS{FS: fs}
+
+	db1, err := linedb.OpenFS(locking, filePath, linedb.Options{Lock: linedb.ExclusiveLock})
+	Need(t, err == nil)
+
+	_, err = linedb.OpenFS(locking, filePath, linedb.Options{Lock: linedb.ExclusiveLock})
+	Need(t, err != nil)
+	WantErr(t, err, linedb.ErrLocked)
+
+	Need(t, db1.Close() == nil)
+
+	db2, err := linedb.OpenFS(locking, filePath, linedb.Options{Lock: linedb.ExclusiveLock})
+	Need(t, err == nil)
+	Want(t, db2.Close() == nil)
+}
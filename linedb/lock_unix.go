@@ -0,0 +1,47 @@
+//go:build unix
+
+package linedb
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// Lock acquires an advisory lock via flock(2), shared or exclusive depending
+// on exclusive, on a sidecar file next to path (path+".lock") rather than on
+// path itself. path is committed by write-temp-then-rename, which replaces
+// its inode on every commit; flock locks an open file description, not a
+// pathname, so a lock taken on path would be silently orphaned on the old
+// inode the moment the first commit renamed a new one into place. The
+// sidecar is never renamed over, so the lock stays attached to the file
+// callers keep looking up by path. The sidecar is created if it doesn't
+// already exist. It returns a function that releases the lock.
+func (OSFS) Lock(path string, exclusive bool) (func() error, error) {
+	lockPath := path + ".lock"
+
+	f, err := os.OpenFile(lockPath, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open %s for locking: %w", lockPath, err)
+	}
+
+	how := syscall.LOCK_SH
+	if exclusive {
+		how = syscall.LOCK_EX
+	}
+
+	if err := syscall.Flock(int(f.Fd()), how|syscall.LOCK_NB); err != nil {
+		f.Close()
+
+		if err == syscall.EWOULDBLOCK {
+			return nil, fmt.Errorf("%w: %s", ErrLocked, path)
+		}
+
+		return nil, fmt.Errorf("cannot lock %s: %w", path, err)
+	}
+
+	return func() error {
+		defer f.Close()
+		return syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+	}, nil
+}
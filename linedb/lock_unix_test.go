@@ -0,0 +1,37 @@
+//go:build unix
+
+package linedb_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "cdop.pt/go/open/assertive"
+	"cdop.pt/go/open/linedb"
+)
+
+// TestOpenExclusiveSurvivesCommit guards against a regression where Lock
+// flocked the backing file's path directly: since commits replace that
+// path's inode via write-temp-then-rename, the lock taken at Open would end
+// up held on an orphaned inode, and a second OpenExclusive would wrongly
+// succeed right after the first commit.
+func TestOpenExclusiveSurvivesCommit(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "db")
+	Need(t, os.WriteFile(filePath, nil, 0600) == nil)
+
+	db1, err := linedb.OpenExclusive(filePath)
+	Need(t, err == nil)
+
+	Need(t, db1.Insert(0, "ln1") == nil)
+
+	_, err = linedb.OpenExclusive(filePath)
+	Need(t, err != nil)
+	WantErr(t, err, linedb.ErrLocked)
+
+	Need(t, db1.Close() == nil)
+
+	db2, err := linedb.OpenExclusive(filePath)
+	Need(t, err == nil)
+	Want(t, db2.Close() == nil)
+}
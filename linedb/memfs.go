@@ -0,0 +1,164 @@
+package linedb
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// MemFS is an in-memory FS implementation, useful for testing linedb and its
+// consumers without touching disk. The zero value is not usable; construct
+// one with NewMemFS.
+type MemFS struct {
+	mu    sync.Mutex
+	files map[string]*memFileData
+}
+
+// NewMemFS returns an empty MemFS.
+func NewMemFS() *MemFS {
+	return &MemFS{files: map[string]*memFileData{}}
+}
+
+type memFileData struct {
+	content []byte
+	mode    os.FileMode
+}
+
+func (fs *MemFS) ReadFile(name string) ([]byte, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	f, ok := fs.files[name]
+	if !ok {
+		return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrNotExist}
+	}
+
+	content := make([]byte, len(f.content))
+	copy(content, f.content)
+
+	return content, nil
+}
+
+func (fs *MemFS) WriteFile(name string, data []byte, perm os.FileMode) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	if f, ok := fs.files[name]; ok {
+		if f.mode&0200 == 0 {
+			return &os.PathError{Op: "open", Path: name, Err: os.ErrPermission}
+		}
+
+		f.content = append([]byte(nil), data...)
+		return nil
+	}
+
+	fs.files[name] = &memFileData{content: append([]byte(nil), data...), mode: perm}
+	return nil
+}
+
+func (fs *MemFS) Stat(name string) (os.FileInfo, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	f, ok := fs.files[name]
+	if !ok {
+		return nil, &os.PathError{Op: "stat", Path: name, Err: os.ErrNotExist}
+	}
+
+	return memFileInfo{name: filepath.Base(name), size: int64(len(f.content)), mode: f.mode}, nil
+}
+
+func (fs *MemFS) Rename(oldpath, newpath string) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	f, ok := fs.files[oldpath]
+	if !ok {
+		return &os.PathError{Op: "rename", Path: oldpath, Err: os.ErrNotExist}
+	}
+
+	fs.files[newpath] = f
+	delete(fs.files, oldpath)
+
+	return nil
+}
+
+func (fs *MemFS) Remove(name string) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	if _, ok := fs.files[name]; !ok {
+		return &os.PathError{Op: "remove", Path: name, Err: os.ErrNotExist}
+	}
+
+	delete(fs.files, name)
+	return nil
+}
+
+func (fs *MemFS) OpenFile(name string, flag int, perm os.FileMode) (File, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	f, ok := fs.files[name]
+	if !ok {
+		if flag&os.O_CREATE == 0 {
+			return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrNotExist}
+		}
+
+		f = &memFileData{mode: perm}
+		fs.files[name] = f
+	} else if f.mode&0200 == 0 && flag&(os.O_WRONLY|os.O_RDWR) != 0 {
+		return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrPermission}
+	}
+
+	if flag&os.O_TRUNC != 0 {
+		f.content = nil
+	}
+
+	return &memFile{fs: fs, name: name}, nil
+}
+
+// memFile is the handle returned by MemFS.OpenFile. Writes are buffered and
+// only committed to the backing MemFS on Close, mirroring how a real file's
+// contents aren't visible to other file descriptors until flushed.
+type memFile struct {
+	fs   *MemFS
+	name string
+	buf  []byte
+}
+
+func (f *memFile) Write(p []byte) (int, error) {
+	f.buf = append(f.buf, p...)
+	return len(p), nil
+}
+
+func (f *memFile) Sync() error {
+	return nil
+}
+
+func (f *memFile) Close() error {
+	f.fs.mu.Lock()
+	defer f.fs.mu.Unlock()
+
+	data, ok := f.fs.files[f.name]
+	if !ok {
+		return &os.PathError{Op: "close", Path: f.name, Err: os.ErrNotExist}
+	}
+
+	data.content = append(data.content, f.buf...)
+	return nil
+}
+
+type memFileInfo struct {
+	name string
+	size int64
+	mode os.FileMode
+}
+
+func (fi memFileInfo) Name() string       { return fi.name }
+func (fi memFileInfo) Size() int64        { return fi.size }
+func (fi memFileInfo) Mode() os.FileMode  { return fi.mode }
+func (fi memFileInfo) ModTime() time.Time { return time.Time{} }
+func (fi memFileInfo) IsDir() bool        { return false }
+func (fi memFileInfo) Sys() any           { return nil }
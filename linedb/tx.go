@@ -0,0 +1,188 @@
+package linedb
+
+import "fmt"
+
+// Tx is a batch of Insert/Update/Delete operations buffered against an
+// in-memory copy of a Database's records, obtained via Database.Begin.
+// Reads through Record, Select and Length see the buffered changes. Commit
+// flushes the whole batch with a single saveToFile call; Rollback discards
+// it. This avoids the one-full-file-rewrite-per-mutation cost of calling
+// Insert/Update/Delete directly when a caller needs to make several edits in
+// sequence.
+//
+// A Tx is not safe for concurrent use, and must not be used after Commit or
+// Rollback.
+type Tx struct {
+	db      *Database
+	records []string
+	closed  bool
+}
+
+// Begin starts a transaction against db, buffering a copy of its current
+// records.
+func (db *Database) Begin() *Tx {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	records := make([]string, len(db.records))
+	copy(records, db.records)
+
+	return &Tx{db: db, records: records}
+}
+
+// Record returns the number-th buffered record, as Database.Record.
+func (tx *Tx) Record(number int) (string, error) {
+	err := tx.checkOpen()
+	if err != nil {
+		return "", err
+	}
+
+	err = checkRecordsEmpty(tx.Length())
+	if err != nil {
+		return "", err
+	}
+
+	err = checkRecordsBounds(tx.Length(), 1, number)
+	if err != nil {
+		return "", err
+	}
+
+	return tx.records[number-1], nil
+}
+
+// Length returns the number of buffered records.
+func (tx *Tx) Length() int {
+	return len(tx.records)
+}
+
+// Select returns the buffered records which cause the filter function to
+// return true, as Database.Select.
+func (tx *Tx) Select(filter func(Rec) bool) []Rec {
+	res := []Rec{}
+
+	for i := range tx.records {
+		if filter(Rec{i + 1, tx.records[i]}) {
+			res = append(res, Rec{i + 1, tx.records[i]})
+		}
+	}
+
+	return res
+}
+
+// Insert buffers the insertion of record at position number, as
+// Database.Insert.
+func (tx *Tx) Insert(number int, record string) error {
+	err := tx.checkOpen()
+	if err != nil {
+		return err
+	}
+
+	err = checkRecordsBounds(tx.Length(), 0, number)
+	if err != nil {
+		return err
+	}
+
+	// special case: inserting empty record on an empty transaction is a noop
+	if tx.Length() == 0 && record == "" {
+		return nil
+	}
+
+	// special case: end of file
+	if number == 0 {
+		tx.records = append(tx.records, record)
+		return nil
+	}
+
+	// general case:
+	records := make([]string, 0, tx.Length()+1)
+	records = append(records, tx.records[0:number-1]...)
+	records = append(records, record)
+	records = append(records, tx.records[number-1:]...)
+
+	tx.records = records
+
+	return nil
+}
+
+// Update buffers the replacement of the number-th record's text, and
+// returns the old text, as Database.Update.
+func (tx *Tx) Update(number int, record string) (string, error) {
+	old, err := tx.Record(number)
+	if err != nil {
+		return "", err
+	}
+
+	tx.records[number-1] = record
+
+	return old, nil
+}
+
+// Delete buffers the removal of the number-th record, and returns its old
+// text, as Database.Delete.
+func (tx *Tx) Delete(number int) (string, error) {
+	old, err := tx.Record(number)
+	if err != nil {
+		return "", err
+	}
+
+	records := make([]string, 0, tx.Length()-1)
+	for i := range tx.records {
+		if i == number-1 {
+			continue
+		}
+
+		records = append(records, tx.records[i])
+	}
+
+	tx.records = records
+
+	return old, nil
+}
+
+// Commit flushes the buffered records with a single saveToFile call. On
+// success, the owning Database's records are replaced with the buffered
+// ones and the transaction is closed. On failure, both the backing file and
+// the Database's in-memory records are left exactly as they were before
+// Begin, and the transaction is closed without being applied.
+func (tx *Tx) Commit() error {
+	err := tx.checkOpen()
+	if err != nil {
+		return err
+	}
+
+	tx.closed = true
+
+	tx.db.mu.Lock()
+	defer tx.db.mu.Unlock()
+
+	err = saveToFile(tx.db.fs, tx.db.filePath, tx.records, tx.db.opts)
+	if err != nil {
+		return err
+	}
+
+	tx.db.records = tx.records
+	tx.db.rebuildIndexesLocked()
+
+	return nil
+}
+
+// Rollback discards the buffered records. The owning Database is left
+// untouched.
+func (tx *Tx) Rollback() error {
+	err := tx.checkOpen()
+	if err != nil {
+		return err
+	}
+
+	tx.closed = true
+
+	return nil
+}
+
+func (tx *Tx) checkOpen() error {
+	if tx.closed {
+		return fmt.Errorf("transaction already closed")
+	}
+
+	return nil
+}
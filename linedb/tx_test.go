@@ -0,0 +1,122 @@
+package linedb_test
+
+import (
+	"testing"
+
+	. "cdop.pt/go/open/assertive"
+	"cdop.pt/go/open/linedb"
+)
+
+func TestTxReadsPendingChanges(t *testing.T) {
+	fs, filePath := mkTestFS(t, "ln1\nln2\nln3")
+
+	db, err := linedb.OpenFS(fs, filePath)
+	Need(t, err == nil)
+
+	tx := db.Begin()
+
+	err = tx.Insert(1, "new1")
+	Need(t, err == nil)
+
+	old, err := tx.Update(3, "new2")
+	Need(t, err == nil)
+	Want(t, old == "ln2")
+
+	Want(t, tx.Length() == 4)
+
+	text, err := tx.Record(1)
+	Need(t, err == nil)
+	Want(t, text == "new1")
+
+	// the db and backing file are untouched until Commit
+	Want(t, db.Length() == 3)
+
+	buf, err := fs.ReadFile(filePath)
+	Need(t, err == nil)
+	Want(t, string(buf) == "ln1\nln2\nln3")
+}
+
+func TestTxCommit(t *testing.T) {
+	fs, filePath := mkTestFS(t, "ln1\nln2\nln3")
+
+	db, err := linedb.OpenFS(fs, filePath)
+	Need(t, err == nil)
+
+	tx := db.Begin()
+
+	err = tx.Insert(1, "new1")
+	Need(t, err == nil)
+
+	_, err = tx.Delete(4) // was ln3 before the insert shifted it to 4
+	Need(t, err == nil)
+
+	err = tx.Commit()
+	Need(t, err == nil)
+
+	Want(t, db.Length() == 3)
+
+	buf, err := fs.ReadFile(filePath)
+	Need(t, err == nil)
+	Want(t, string(buf) == "new1\nln1\nln2")
+}
+
+func TestTxRollback(t *testing.T) {
+	fs, filePath := mkTestFS(t, "ln1\nln2\nln3")
+
+	db, err := linedb.OpenFS(fs, filePath)
+	Need(t, err == nil)
+
+	tx := db.Begin()
+
+	err = tx.Insert(1, "new1")
+	Need(t, err == nil)
+
+	err = tx.Rollback()
+	Need(t, err == nil)
+
+	Want(t, db.Length() == 3)
+
+	buf, err := fs.ReadFile(filePath)
+	Need(t, err == nil)
+	Want(t, string(buf) == "ln1\nln2\nln3")
+}
+
+func TestTxCommitFailureLeavesStateUnchanged(t *testing.T) {
+	fs, filePath := mkTestFS(t, "ln1\nln2\nln3")
+	failing := failingFS{FS: fs, failSubstr: ".tmp"}
+
+	db, err := linedb.OpenFS(failing, filePath)
+	Need(t, err == nil)
+
+	tx := db.Begin()
+
+	err = tx.Insert(1, "new1")
+	Need(t, err == nil)
+
+	err = tx.Commit()
+	Need(t, err != nil)
+
+	Want(t, db.Length() == 3)
+
+	buf, err := fs.ReadFile(filePath)
+	Need(t, err == nil)
+	Want(t, string(buf) == "ln1\nln2\nln3")
+}
+
+func TestTxClosedReuseFails(t *testing.T) {
+	fs, filePath := mkTestFS(t, "ln1")
+
+	db, err := linedb.OpenFS(fs, filePath)
+	Need(t, err == nil)
+
+	tx := db.Begin()
+
+	err = tx.Commit()
+	Need(t, err == nil)
+
+	err = tx.Rollback()
+	Want(t, err != nil)
+
+	err = tx.Insert(0, "ln2")
+	Want(t, err != nil)
+}